@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package assets fetches and caches a signed manifest describing the
+// latest upstream hypervisor assets (kernel, image, hypervisor binary),
+// so "list --cc-all" can flag containers whose local assets are stale
+// relative to upstream, not merely relative to the currently configured
+// local files (see runtime's getStaleAssets).
+package assets
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrOffline is returned by Fetcher.Fetch when Offline is set, so
+// callers can fall back to local-only comparison without treating it as
+// a hard failure.
+var ErrOffline = errors.New("assets: network access disabled (--offline)")
+
+const (
+	manifestCacheFile = "asset-manifest.json"
+	etagCacheFile     = "asset-manifest.etag"
+)
+
+// Asset describes a single upstream asset's version and content digest.
+type Asset struct {
+	Version string `json:"version"`
+	Digest  string `json:"digest"`
+}
+
+// Manifest is the signed document published upstream, listing the
+// current version and digest of each hypervisor asset.
+type Manifest struct {
+	Kernel     Asset `json:"kernel"`
+	Image      Asset `json:"image"`
+	Hypervisor Asset `json:"hypervisor"`
+}
+
+// Fetcher fetches and caches a Manifest from URL, verifying it against
+// PublicKey when one is configured.
+type Fetcher struct {
+	// URL is the manifest endpoint, e.g. RuntimeConfig's
+	// "hypervisor.asset_manifest_url".
+	URL string
+	// PublicKey, if non-nil, is used to verify a detached ed25519
+	// signature fetched from URL+".sig".
+	PublicKey ed25519.PublicKey
+	// CacheDir is where the manifest and its ETag are cached, e.g.
+	// under the runtime root.
+	CacheDir string
+	// TTL is how long a cached manifest is considered fresh before a
+	// revalidation request is made.
+	TTL time.Duration
+	// Offline suppresses all network access; Fetch returns ErrOffline.
+	Offline bool
+
+	// Client is the HTTP client used to fetch the manifest; defaults
+	// to http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// Fetch returns the current Manifest, using the on-disk cache when it is
+// within TTL, revalidating with the server via ETag/If-Modified-Since
+// otherwise.
+func (f *Fetcher) Fetch() (*Manifest, error) {
+	if f.Offline {
+		return nil, ErrOffline
+	}
+
+	manifestPath := filepath.Join(f.CacheDir, manifestCacheFile)
+	etagPath := filepath.Join(f.CacheDir, etagCacheFile)
+
+	if info, err := os.Stat(manifestPath); err == nil {
+		if time.Since(info.ModTime()) < f.TTL {
+			return f.loadCached(manifestPath)
+		}
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodGet, f.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if etag, err := ioutil.ReadFile(etagPath); err == nil {
+		req.Header.Set("If-None-Match", string(etag))
+	}
+
+	if info, err := os.Stat(manifestPath); err == nil {
+		req.Header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The server is unreachable: fall back to whatever is cached,
+		// if anything, rather than failing outright.
+		if m, cacheErr := f.loadCached(manifestPath); cacheErr == nil {
+			return m, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		_ = os.Chtimes(manifestPath, time.Now(), time.Now())
+		return f.loadCached(manifestPath)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("assets: fetching manifest: unexpected status %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.PublicKey != nil {
+		if err := f.verify(client, body); err != nil {
+			return nil, err
+		}
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("assets: malformed manifest: %v", err)
+	}
+
+	if err := os.MkdirAll(f.CacheDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(manifestPath, body, 0644); err != nil {
+		return nil, err
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		_ = ioutil.WriteFile(etagPath, []byte(etag), 0644)
+	}
+
+	return &m, nil
+}
+
+func (f *Fetcher) loadCached(manifestPath string) (*Manifest, error) {
+	body, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("assets: malformed cached manifest: %v", err)
+	}
+
+	return &m, nil
+}
+
+// verify fetches the detached signature at f.URL+".sig" and checks it
+// against body using f.PublicKey.
+func (f *Fetcher) verify(client *http.Client, body []byte) error {
+	resp, err := client.Get(f.URL + ".sig")
+	if err != nil {
+		return fmt.Errorf("assets: fetching manifest signature: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("assets: fetching manifest signature: unexpected status %s", resp.Status)
+	}
+
+	sig, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(f.PublicKey, body, sig) {
+		return errors.New("assets: manifest signature verification failed")
+	}
+
+	return nil
+}
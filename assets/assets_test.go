@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func tempCacheDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cc-assets-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func mustMarshal(t *testing.T, m Manifest) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %v", err)
+	}
+
+	return b
+}
+
+func TestFetchSignatureFailure(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	body := mustMarshal(t, Manifest{Kernel: Asset{Version: "1.0", Digest: "sha256:abc"}})
+
+	// Sign a different payload, so the signature on "body" never
+	// validates against pub.
+	badSig := ed25519.Sign(priv, []byte("not the manifest"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.json.sig" {
+			w.Write(badSig)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{
+		URL:       srv.URL + "/manifest.json",
+		PublicKey: pub,
+		CacheDir:  tempCacheDir(t),
+		TTL:       time.Hour,
+	}
+
+	if _, err := f.Fetch(); err == nil {
+		t.Fatal("expected a signature verification error")
+	}
+}
+
+func TestFetchSignatureSuccess(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	body := mustMarshal(t, Manifest{Kernel: Asset{Version: "1.0", Digest: "sha256:abc"}})
+	sig := ed25519.Sign(priv, body)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/manifest.json.sig" {
+			w.Write(sig)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	f := &Fetcher{
+		URL:       srv.URL + "/manifest.json",
+		PublicKey: pub,
+		CacheDir:  tempCacheDir(t),
+		TTL:       time.Hour,
+	}
+
+	m, err := f.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if m.Kernel.Version != "1.0" || m.Kernel.Digest != "sha256:abc" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+}
+
+func TestFetchTTLExpiry(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(mustMarshal(t, Manifest{Kernel: Asset{Version: "1.0"}}))
+	}))
+	defer srv.Close()
+
+	cacheDir := tempCacheDir(t)
+
+	f := &Fetcher{
+		URL:      srv.URL,
+		CacheDir: cacheDir,
+		TTL:      50 * time.Millisecond,
+	}
+
+	if _, err := f.Fetch(); err != nil {
+		t.Fatalf("unexpected error on first fetch: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected 1 request after first fetch, got %d", requests)
+	}
+
+	// Within TTL: should be served from cache, no new request.
+	if _, err := f.Fetch(); err != nil {
+		t.Fatalf("unexpected error on cached fetch: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("expected the cached fetch not to hit the server, got %d requests", requests)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Past TTL: should revalidate with the server.
+	if _, err := f.Fetch(); err != nil {
+		t.Fatalf("unexpected error on revalidated fetch: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the expired fetch to hit the server, got %d requests", requests)
+	}
+}
+
+func TestFetchNetworkUnavailableFallsBackToCache(t *testing.T) {
+	cacheDir := tempCacheDir(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(mustMarshal(t, Manifest{Kernel: Asset{Version: "1.0"}}))
+	}))
+
+	f := &Fetcher{
+		URL:      srv.URL,
+		CacheDir: cacheDir,
+		TTL:      0,
+	}
+
+	if _, err := f.Fetch(); err != nil {
+		t.Fatalf("unexpected error priming the cache: %v", err)
+	}
+
+	// Take the server down so the next fetch can't reach it, but leave
+	// the cache on disk.
+	srv.Close()
+
+	m, err := f.Fetch()
+	if err != nil {
+		t.Fatalf("expected the network-unavailable fetch to fall back to cache, got error: %v", err)
+	}
+
+	if m.Kernel.Version != "1.0" {
+		t.Errorf("unexpected manifest from cache fallback: %+v", m)
+	}
+}
+
+func TestFetchOffline(t *testing.T) {
+	f := &Fetcher{
+		URL:      "http://unused.invalid/manifest.json",
+		CacheDir: tempCacheDir(t),
+		TTL:      time.Hour,
+		Offline:  true,
+	}
+
+	if _, err := f.Fetch(); err != ErrOffline {
+		t.Fatalf("expected ErrOffline, got %v", err)
+	}
+}
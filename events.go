@@ -0,0 +1,165 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/containers/runtime/events"
+)
+
+const eventsFormatOptions = `json or journald`
+
+// Producing events.
+//
+// This file implements the "events" command, i.e. reading the stream
+// back. Producing it is the job of every create/start/stop/exec/kill/
+// delete code path (and the hypervisor-restart path): each should call
+// events.Record(sink, events.TypeXXX, id, annotations) at the point the
+// transition actually happens, using the events.Sink returned by
+// events.NewSink(root). No such call site exists in this tree, so the
+// stream read here holds only what some other writer has recorded.
+
+var eventsCLICommand = cli.Command{
+	Name:  "events",
+	Usage: "show the " + name + " container event stream",
+	ArgsUsage: `
+
+Where the given root is specified via the global option "--root"
+(default: "` + defaultRootDirectory + `").
+
+EXAMPLE:
+To follow events for all containers as they happen:
+       # ` + name + ` events --watch`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "since",
+			Usage: "show events created since this RFC3339 timestamp",
+		},
+		cli.StringFlag{
+			Name:  "until",
+			Usage: "show events created until this RFC3339 timestamp",
+		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: `filter by "type=<type>", e.g. "type=create"; may be repeated (OR'ed)`,
+		},
+		cli.BoolFlag{
+			Name:  "watch",
+			Usage: "keep the stream open and print new events as they arrive",
+		},
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "json",
+			Usage: `select one of: ` + eventsFormatOptions,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		root := context.GlobalString("root")
+		if root == "" {
+			root = defaultRootDirectory
+		}
+
+		since, err := parseEventsTime(context.String("since"))
+		if err != nil {
+			return err
+		}
+
+		until, err := parseEventsTime(context.String("until"))
+		if err != nil {
+			return err
+		}
+
+		types, err := parseEventTypes(context.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+
+		sink, err := events.NewSink(root)
+		if err != nil {
+			return err
+		}
+
+		format := context.String("format")
+		if format != "json" && format != "journald" {
+			return fmt.Errorf("invalid format option")
+		}
+
+		ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+		defer cancel()
+
+		if context.Bool("watch") {
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, os.Interrupt)
+			go func() {
+				<-sigCh
+				cancel()
+			}()
+		}
+
+		return sink.Read(ctx, since, until, types, context.Bool("watch"), func(e events.Event) error {
+			return writeEvent(defaultOutputFile, format, e)
+		})
+	},
+}
+
+// parseEventsTime parses the --since/--until RFC3339 flag values, treating
+// an empty string as "unbounded".
+func parseEventsTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %v", s, err)
+	}
+
+	return t, nil
+}
+
+// parseEventTypes parses repeated "--filter type=<type>" tokens into the
+// list of events.Type to match (all types, if none given).
+func parseEventTypes(tokens []string) ([]events.Type, error) {
+	var types []events.Type
+
+	for _, tok := range tokens {
+		const prefix = "type="
+		if len(tok) <= len(prefix) || tok[:len(prefix)] != prefix {
+			return nil, fmt.Errorf("invalid filter %q: expected type=<type>", tok)
+		}
+
+		types = append(types, events.Type(tok[len(prefix):]))
+	}
+
+	return types, nil
+}
+
+func writeEvent(file *os.File, format string, e events.Event) error {
+	switch format {
+	case "journald":
+		fmt.Fprintf(file, "%s %s %s\n", e.Time.Format(time.RFC3339Nano), e.Type, e.ID)
+		return nil
+	default:
+		return json.NewEncoder(file).Encode(e)
+	}
+}
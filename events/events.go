@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events records and tails container lifecycle transitions,
+// modeled on libpod/events. Events are written to a pluggable sink:
+// journald when available, otherwise an append-only JSON-lines logfile
+// under the runtime root.
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// Type identifies the kind of lifecycle transition an Event describes.
+type Type string
+
+const (
+	// TypeCreate is recorded when a container is created.
+	TypeCreate Type = "create"
+	// TypeStart is recorded when a container is started.
+	TypeStart Type = "start"
+	// TypeStop is recorded when a container is stopped.
+	TypeStop Type = "stop"
+	// TypeExec is recorded when a process is executed inside a
+	// container.
+	TypeExec Type = "exec"
+	// TypeKill is recorded when a signal is sent to a container.
+	TypeKill Type = "kill"
+	// TypeDelete is recorded when a container is deleted.
+	TypeDelete Type = "delete"
+	// TypeOOM is recorded when a container is killed by the OOM
+	// killer.
+	TypeOOM Type = "oom"
+	// TypeHypervisorRestart is recorded when a container's hypervisor
+	// is restarted.
+	TypeHypervisorRestart Type = "hypervisor-restart"
+)
+
+// Event records a single container lifecycle transition.
+type Event struct {
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+	// Type is the kind of transition.
+	Type Type `json:"type"`
+	// ID is the ID of the container the event relates to.
+	ID string `json:"id"`
+	// Annotations carries event-specific details, e.g. the exit code
+	// of a "stop" event or the signal of a "kill" event.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Writer persists Events to a sink.
+type Writer interface {
+	Write(Event) error
+}
+
+// Reader reads Events back from a sink.
+type Reader interface {
+	// Read calls fn for every event in [since, until] whose Type is in
+	// types (all types, if empty), in chronological order. If follow
+	// is true, Read blocks delivering new events as they arrive until
+	// ctx is done.
+	Read(ctx context.Context, since, until time.Time, types []Type, follow bool, fn func(Event) error) error
+}
+
+// Sink is a combined Writer and Reader backed by a single store.
+type Sink interface {
+	Writer
+	Reader
+}
+
+// NewSink returns the best available Sink: journald if the system has a
+// running journal, otherwise a JSON-lines logfile under runtimeRoot.
+func NewSink(runtimeRoot string) (Sink, error) {
+	if journaldAvailable() {
+		return newJournaldSink(), nil
+	}
+
+	return newFileSink(runtimeRoot)
+}
+
+// Record stamps the current time onto a lifecycle transition and writes
+// it to w. This is the call every create/start/stop/exec/kill/delete
+// code path (and the hypervisor-restart path) should make at the point
+// the transition actually happens, passing the Writer returned by
+// NewSink.
+//
+// Those call sites don't exist in this tree: it has no create/start/
+// stop/exec/kill/delete command implementations to wire Record into, so
+// nothing in this repo calls it yet. It ships now so "events" has
+// something to read once they land, but until then the event stream
+// stays empty - "events"/"list --watch" only show real transitions once
+// a lifecycle command is updated to call this.
+func Record(w Writer, typ Type, id string, annotations map[string]string) error {
+	return w.Write(Event{
+		Time:        time.Now(),
+		Type:        typ,
+		ID:          id,
+		Annotations: annotations,
+	})
+}
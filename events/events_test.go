@@ -0,0 +1,187 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestFileSink(t *testing.T) *fileSink {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cc-events-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	sink, err := newFileSink(dir)
+	if err != nil {
+		t.Fatalf("failed to create file sink: %v", err)
+	}
+
+	return sink
+}
+
+func readAll(t *testing.T, sink *fileSink, since, until time.Time, types []Type) []Event {
+	t.Helper()
+
+	var got []Event
+	err := sink.Read(context.Background(), since, until, types, false, func(e Event) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	return got
+}
+
+func TestFileSinkWriteRead(t *testing.T) {
+	sink := newTestFileSink(t)
+
+	want := []Event{
+		{Time: time.Unix(100, 0), Type: TypeCreate, ID: "c1"},
+		{Time: time.Unix(200, 0), Type: TypeStart, ID: "c1"},
+		{Time: time.Unix(300, 0), Type: TypeStop, ID: "c1"},
+	}
+
+	for _, e := range want {
+		if err := sink.Write(e); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	got := readAll(t, sink, time.Time{}, time.Time{}, nil)
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+
+	for i, e := range got {
+		if !e.Time.Equal(want[i].Time) || e.Type != want[i].Type || e.ID != want[i].ID {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+}
+
+func TestFileSinkSinceUntil(t *testing.T) {
+	sink := newTestFileSink(t)
+
+	for _, e := range []Event{
+		{Time: time.Unix(100, 0), Type: TypeCreate, ID: "c1"},
+		{Time: time.Unix(200, 0), Type: TypeStart, ID: "c1"},
+		{Time: time.Unix(300, 0), Type: TypeStop, ID: "c1"},
+	} {
+		if err := sink.Write(e); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	got := readAll(t, sink, time.Unix(150, 0), time.Unix(250, 0), nil)
+	if len(got) != 1 || got[0].Type != TypeStart {
+		t.Fatalf("got %+v, want only the TypeStart event", got)
+	}
+}
+
+func TestFileSinkFilterByType(t *testing.T) {
+	sink := newTestFileSink(t)
+
+	for _, e := range []Event{
+		{Time: time.Unix(100, 0), Type: TypeCreate, ID: "c1"},
+		{Time: time.Unix(200, 0), Type: TypeStop, ID: "c1"},
+		{Time: time.Unix(300, 0), Type: TypeStop, ID: "c2"},
+	} {
+		if err := sink.Write(e); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	got := readAll(t, sink, time.Time{}, time.Time{}, []Type{TypeStop})
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	for _, e := range got {
+		if e.Type != TypeStop {
+			t.Errorf("unexpected event type %v", e.Type)
+		}
+	}
+}
+
+func TestFileSinkFollowSeesLateWrites(t *testing.T) {
+	sink := newTestFileSink(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	seen := make(chan Event, 1)
+	readErr := make(chan error, 1)
+
+	go func() {
+		readErr <- sink.Read(ctx, time.Time{}, time.Time{}, nil, true, func(e Event) error {
+			seen <- e
+			return nil
+		})
+	}()
+
+	// Give Read a moment to perform its initial scan before the event
+	// is written, exercising the "arrives while following" path rather
+	// than the "already present" path covered by TestFileSinkWriteRead.
+	time.Sleep(50 * time.Millisecond)
+
+	if err := sink.Write(Event{Time: time.Unix(400, 0), Type: TypeDelete, ID: "c1"}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	select {
+	case e := <-seen:
+		if e.Type != TypeDelete || e.ID != "c1" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the followed event")
+	}
+
+	cancel()
+
+	if err := <-readErr; err != nil {
+		t.Errorf("Read returned an error after cancellation: %v", err)
+	}
+}
+
+func TestRecordWritesEvent(t *testing.T) {
+	sink := newTestFileSink(t)
+
+	if err := Record(sink, TypeKill, "c1", map[string]string{"signal": "SIGTERM"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	got := readAll(t, sink, time.Time{}, time.Time{}, nil)
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+
+	if got[0].Type != TypeKill || got[0].ID != "c1" || got[0].Annotations["signal"] != "SIGTERM" {
+		t.Errorf("unexpected event: %+v", got[0])
+	}
+
+	if got[0].Time.IsZero() {
+		t.Error("expected Record to stamp a non-zero time")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// logFileName is the JSON-lines logfile written under the runtime root
+// when journald is unavailable.
+const logFileName = "events.log"
+
+// fileSink is an append-only JSON-lines Sink, one Event per line.
+type fileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newFileSink(runtimeRoot string) (*fileSink, error) {
+	path := filepath.Join(runtimeRoot, logFileName)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	return &fileSink{path: path}, nil
+}
+
+func (s *fileSink) Write(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+
+	return enc.Encode(e)
+}
+
+func (s *fileSink) Read(ctx context.Context, since, until time.Time, types []Type, follow bool, fn func(Event) error) error {
+	wanted := make(map[Type]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	emit := func(e Event) (bool, error) {
+		if len(wanted) > 0 && !wanted[e.Type] {
+			return true, nil
+		}
+		if !since.IsZero() && e.Time.Before(since) {
+			return true, nil
+		}
+		if !until.IsZero() && e.Time.After(until) {
+			return true, nil
+		}
+
+		return true, fn(e)
+	}
+
+	offset, err := s.scan(emit)
+	if err != nil {
+		return err
+	}
+
+	if !follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			offset, err = s.scanFrom(offset, emit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scan reads every event in the logfile from the start, returning the
+// byte offset of the end of the file.
+func (s *fileSink) scan(emit func(Event) (bool, error)) (int64, error) {
+	return s.scanFrom(0, emit)
+}
+
+// scanFrom reads events starting at byte offset from, returning the new
+// end-of-file offset.
+func (s *fileSink) scanFrom(from int64, emit func(Event) (bool, error)) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return from, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(from, 0); err != nil {
+		return from, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	offset := from
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		offset += int64(len(line)) + 1
+
+		var e Event
+		if err := json.Unmarshal(line, &e); err != nil {
+			return offset, err
+		}
+
+		if _, err := emit(e); err != nil {
+			return offset, err
+		}
+	}
+
+	return offset, scanner.Err()
+}
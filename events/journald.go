@@ -0,0 +1,127 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-systemd/journal"
+	"github.com/coreos/go-systemd/sdjournal"
+)
+
+// journalIdentifier tags every entry this package writes, so reads can
+// select just our events out of the wider system journal.
+const journalIdentifier = "cc-events"
+
+// journaldAvailable reports whether the running system has a usable
+// systemd-journald socket.
+func journaldAvailable() bool {
+	return journal.Enabled()
+}
+
+// journaldSink writes Events as journald entries and reads them back via
+// sdjournal, filtering on journalIdentifier.
+type journaldSink struct{}
+
+func newJournaldSink() *journaldSink {
+	return &journaldSink{}
+}
+
+func (s *journaldSink) Write(e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return journal.Send(string(payload), journal.PriInfo, map[string]string{
+		"SYSLOG_IDENTIFIER": journalIdentifier,
+		"CC_EVENT_TYPE":     string(e.Type),
+		"CC_EVENT_ID":       e.ID,
+	})
+}
+
+func (s *journaldSink) Read(ctx context.Context, since, until time.Time, types []Type, follow bool, fn func(Event) error) error {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	if err := j.AddMatch("SYSLOG_IDENTIFIER=" + journalIdentifier); err != nil {
+		return err
+	}
+
+	if since.IsZero() {
+		err = j.SeekHead()
+	} else {
+		err = j.SeekRealtimeUsec(uint64(since.UnixNano() / 1000))
+	}
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[Type]bool, len(types))
+	for _, t := range types {
+		wanted[t] = true
+	}
+
+	for {
+		n, err := j.Next()
+		if err != nil {
+			return err
+		}
+
+		if n == 0 {
+			if !follow {
+				return nil
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			j.Wait(time.Second)
+			continue
+		}
+
+		entry, err := j.GetEntry()
+		if err != nil {
+			return err
+		}
+
+		msg, ok := entry.Fields["MESSAGE"]
+		if !ok {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal([]byte(msg), &e); err != nil {
+			return fmt.Errorf("malformed journald event: %v", err)
+		}
+
+		if len(wanted) > 0 && !wanted[e.Type] {
+			continue
+		}
+		if !until.IsZero() && e.Time.After(until) {
+			return nil
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filters implements the "key=value" predicate grammar accepted by
+// the "list" command's repeatable "--filter" flag, following the
+// conventions users already know from "podman ps"/"docker ps".
+package filters
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Record is the minimal view of a container that a Filters tree can be
+// matched against. Callers adapt their own state types to it so this
+// package has no dependency on them.
+type Record interface {
+	// Field returns the string value of the named well-known field
+	// ("id", "status", "state", "bundle", "owner" or "stale", plus
+	// "ownerUID", which "owner" predicates also consult) and whether that
+	// field is currently known. A field reported as unknown (ok ==
+	// false) causes any predicate against it to fail to match rather
+	// than error, so callers may supply partial records.
+	Field(key string) (value string, ok bool)
+	// Annotation returns the value of the named "annotation.<key>"
+	// and whether it is present.
+	Annotation(key string) (value string, ok bool)
+}
+
+// knownKeys are the well-known fields recognised alongside the
+// "annotation.<key>" family.
+var knownKeys = map[string]bool{
+	"status": true,
+	"state":  true,
+	"id":     true,
+	"bundle": true,
+	"owner":  true,
+	"stale":  true,
+}
+
+type predicate struct {
+	key   string
+	value string
+}
+
+func (p predicate) match(r Record) (bool, error) {
+	if name, ok := annotationKey(p.key); ok {
+		v, known := r.Annotation(name)
+		return known && v == p.value, nil
+	}
+
+	v, ok := r.Field(p.key)
+	if !ok {
+		return false, nil
+	}
+
+	switch p.key {
+	case "id":
+		return strings.HasPrefix(v, p.value), nil
+	case "bundle":
+		return filepath.Match(p.value, v)
+	case "owner":
+		// v may be the resolved username (e.g. "root") rather than
+		// "#<uid>", so also compare against the numeric uid when the
+		// Record can supply one.
+		want := strings.TrimPrefix(p.value, "#")
+		if strings.TrimPrefix(v, "#") == want {
+			return true, nil
+		}
+		if uid, ok := r.Field("ownerUID"); ok {
+			return uid == want, nil
+		}
+		return false, nil
+	case "stale":
+		for _, s := range strings.Split(v, ",") {
+			if s == p.value {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return v == p.value, nil
+	}
+}
+
+func annotationKey(key string) (string, bool) {
+	const prefix = "annotation."
+	if !strings.HasPrefix(key, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(key, prefix), true
+}
+
+// predicateGroup holds the predicates sharing a single key; a group
+// matches if any one of its predicates matches (logical OR).
+type predicateGroup struct {
+	key        string
+	predicates []predicate
+}
+
+// Filters is a parsed "--filter" predicate tree. Distinct keys are AND'ed
+// together; repeated "--filter" flags sharing the same key are OR'ed
+// together.
+type Filters struct {
+	groups []predicateGroup
+}
+
+// Parse parses the list of raw "--filter" values (each a "key=value"
+// token, or "annotation.<key>=value") into a Filters tree.
+func Parse(tokens []string) (*Filters, error) {
+	order := []string{}
+	byKey := make(map[string]*predicateGroup)
+
+	for _, tok := range tokens {
+		parts := strings.SplitN(tok, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid filter %q: expected key=value", tok)
+		}
+
+		key, value := parts[0], parts[1]
+
+		if _, ok := annotationKey(key); !ok && !knownKeys[key] {
+			return nil, fmt.Errorf("unknown filter key %q", key)
+		}
+
+		g, ok := byKey[key]
+		if !ok {
+			g = &predicateGroup{key: key}
+			byKey[key] = g
+			order = append(order, key)
+		}
+
+		g.predicates = append(g.predicates, predicate{key: key, value: value})
+	}
+
+	f := &Filters{}
+	for _, key := range order {
+		f.groups = append(f.groups, *byKey[key])
+	}
+
+	return f, nil
+}
+
+// Match reports whether r satisfies every predicate group (logical AND),
+// where a group itself is satisfied if any of its predicates match
+// (logical OR). A nil Filters matches everything.
+func (f *Filters) Match(r Record) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	for _, g := range f.groups {
+		matched := false
+
+		for _, p := range g.predicates {
+			ok, err := p.match(r)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Without splits f into the predicate groups not matching key ("rest")
+// and the single group that does ("only", nil if key is not filtered
+// on). This lets callers defer expensive field lookups (such as a
+// container's on-disk owner) until the cheaper predicates have already
+// excluded a candidate.
+func (f *Filters) Without(key string) (rest *Filters, only *Filters) {
+	if f == nil {
+		return nil, nil
+	}
+
+	rest = &Filters{}
+
+	for _, g := range f.groups {
+		if g.key == key {
+			g := g
+			only = &Filters{groups: []predicateGroup{g}}
+			continue
+		}
+
+		rest.groups = append(rest.groups, g)
+	}
+
+	return rest, only
+}
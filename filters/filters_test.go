@@ -0,0 +1,252 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filters
+
+import "testing"
+
+type fakeRecord struct {
+	fields      map[string]string
+	annotations map[string]string
+}
+
+func (r fakeRecord) Field(key string) (string, bool) {
+	v, ok := r.fields[key]
+	return v, ok
+}
+
+func (r fakeRecord) Annotation(key string) (string, bool) {
+	v, ok := r.annotations[key]
+	return v, ok
+}
+
+func TestParseUnknownKeyRejected(t *testing.T) {
+	if _, err := Parse([]string{"bogus=value"}); err == nil {
+		t.Fatal("expected an error for an unknown filter key")
+	}
+}
+
+func TestParseMalformedTokenRejected(t *testing.T) {
+	if _, err := Parse([]string{"no-equals-sign"}); err == nil {
+		t.Fatal("expected an error for a token without \"=\"")
+	}
+}
+
+func TestMatchBundleGlob(t *testing.T) {
+	f, err := Parse([]string{"bundle=/run/bundles/*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	match, bundle := fakeRecord{fields: map[string]string{"bundle": "/run/bundles/foo"}}, "/run/bundles/foo"
+	ok, err := f.Match(match)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Errorf("expected %q to match glob /run/bundles/*", bundle)
+	}
+
+	noMatch := fakeRecord{fields: map[string]string{"bundle": "/other/path"}}
+	ok, err = f.Match(noMatch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected /other/path not to match glob /run/bundles/*")
+	}
+}
+
+func TestMatchIDPrefix(t *testing.T) {
+	f, err := Parse([]string{"id=abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ok, err := f.Match(fakeRecord{fields: map[string]string{"id": "abc123"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected \"abc123\" to match prefix \"abc\"")
+	}
+
+	ok, err = f.Match(fakeRecord{fields: map[string]string{"id": "xyz789"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected \"xyz789\" not to match prefix \"abc\"")
+	}
+}
+
+func TestMatchAnnotation(t *testing.T) {
+	f, err := Parse([]string{"annotation.foo=bar"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ok, err := f.Match(fakeRecord{annotations: map[string]string{"foo": "bar"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected annotation.foo=bar to match")
+	}
+
+	ok, err = f.Match(fakeRecord{annotations: map[string]string{"foo": "baz"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected annotation.foo=bar not to match foo=baz")
+	}
+}
+
+func TestMatchDistinctKeysAreANDed(t *testing.T) {
+	f, err := Parse([]string{"status=running", "id=abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	both := fakeRecord{fields: map[string]string{"status": "running", "id": "abc123"}}
+	ok, err := f.Match(both)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected a record matching both filters to match")
+	}
+
+	onlyStatus := fakeRecord{fields: map[string]string{"status": "running", "id": "xyz"}}
+	ok, err = f.Match(onlyStatus)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected a record matching only one of two AND'ed filters not to match")
+	}
+}
+
+func TestMatchRepeatedKeysAreORed(t *testing.T) {
+	f, err := Parse([]string{"status=running", "status=paused"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, status := range []string{"running", "paused"} {
+		ok, err := f.Match(fakeRecord{fields: map[string]string{"status": status}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !ok {
+			t.Errorf("expected status=%s to match one of the OR'ed values", status)
+		}
+	}
+
+	ok, err := f.Match(fakeRecord{fields: map[string]string{"status": "stopped"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected status=stopped not to match either OR'ed value")
+	}
+}
+
+func TestMatchNilFiltersMatchesEverything(t *testing.T) {
+	var f *Filters
+
+	ok, err := f.Match(fakeRecord{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected a nil Filters to match everything")
+	}
+}
+
+// TestMatchIndependentOfOutputFlags documents that a Filters tree only
+// ever inspects the Record given to it - "list"'s --quiet and --format
+// flags only change how a container already selected by --filter is
+// rendered, not whether it is selected in the first place.
+func TestMatchIndependentOfOutputFlags(t *testing.T) {
+	f, err := Parse([]string{"status=running"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := fakeRecord{fields: map[string]string{"status": "running", "id": "abc123"}}
+
+	for _, quiet := range []bool{true, false} {
+		for _, format := range []string{"table", "json"} {
+			ok, err := f.Match(rec)
+			if err != nil {
+				t.Fatalf("unexpected error (quiet=%v, format=%s): %v", quiet, format, err)
+			}
+			if !ok {
+				t.Errorf("expected a matching record to match regardless of quiet=%v, format=%s", quiet, format)
+			}
+		}
+	}
+}
+
+// TestMatchOwnerByUID documents that "owner=#1000" still matches a
+// Record whose resolved "owner" field is a username (e.g. "root") as
+// long as it also exposes the numeric "ownerUID".
+func TestMatchOwnerByUID(t *testing.T) {
+	f, err := Parse([]string{"owner=#1000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := fakeRecord{fields: map[string]string{"owner": "root", "ownerUID": "1000"}}
+
+	ok, err := f.Match(rec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected owner=#1000 to match a resolved username via ownerUID")
+	}
+
+	other := fakeRecord{fields: map[string]string{"owner": "root", "ownerUID": "1001"}}
+	ok, err = f.Match(other)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected owner=#1000 not to match a different ownerUID")
+	}
+}
+
+func TestWithout(t *testing.T) {
+	f, err := Parse([]string{"status=running", "owner=#1000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rest, only := f.Without("owner")
+
+	if only == nil {
+		t.Fatal("expected the \"owner\" group to be split out")
+	}
+
+	if ok, _ := rest.Match(fakeRecord{fields: map[string]string{"status": "running"}}); !ok {
+		t.Error("expected rest to still match on status")
+	}
+
+	if ok, _ := only.Match(fakeRecord{fields: map[string]string{"owner": "#1000"}}); !ok {
+		t.Error("expected only to match on owner")
+	}
+}
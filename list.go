@@ -16,21 +16,111 @@
 package main
 
 import (
+	stdcontext "context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/urfave/cli"
 
 	oci "github.com/containers/virtcontainers/pkg/oci"
+
+	"github.com/containers/runtime/assets"
+	"github.com/containers/runtime/events"
+	"github.com/containers/runtime/filters"
+	"github.com/containers/runtime/user"
+)
+
+const formatOptions = `table, json, or a Go template such as '{{.ID}} {{.Status}}'
+(see https://golang.org/pkg/text/template/). Prefix the template with
+"table " to render it through the tabular writer, or use the shortcut
+template "{{json .}}" to emit each row as a line of JSON.`
+
+// ContainerState represents a container's position in its lifecycle,
+// modeled on libpod's container-state machine.
+type ContainerState int
+
+const (
+	// StateUnknown indicates the state could not be determined.
+	StateUnknown ContainerState = iota
+	// StateConfigured indicates the container has been configured but
+	// not yet created.
+	StateConfigured
+	// StateCreated indicates the container has been created but not
+	// yet started.
+	StateCreated
+	// StateRunning indicates the container is running.
+	StateRunning
+	// StatePaused indicates the container has been paused.
+	StatePaused
+	// StateStopped indicates the container has been stopped.
+	StateStopped
+	// StateExited indicates the container's process has exited.
+	StateExited
 )
 
-const formatOptions = `table or json`
+// String returns the stable, lower-case name used for StateXXX as the
+// JSON "state" field and by the "state" filter. The tabular writer's
+// STATUS column shows the legacy Status string, not this name.
+func (s ContainerState) String() string {
+	switch s {
+	case StateConfigured:
+		return "configured"
+	case StateCreated:
+		return "created"
+	case StateRunning:
+		return "running"
+	case StatePaused:
+		return "paused"
+	case StateStopped:
+		return "stopped"
+	case StateExited:
+		return "exited"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes a ContainerState as its stable string name rather
+// than the underlying int, so the "state" field survives enum changes.
+func (s ContainerState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// containerStateFromStatus maps the legacy free-form status string
+// produced by oci.StatusToOCIState onto the typed ContainerState enum.
+func containerStateFromStatus(status string) ContainerState {
+	switch status {
+	case "configured":
+		return StateConfigured
+	case "created":
+		return StateCreated
+	case "running":
+		return StateRunning
+	case "paused":
+		return StatePaused
+	case "stopped":
+		return StateStopped
+	case "exited":
+		return StateExited
+	default:
+		return StateUnknown
+	}
+}
 
 // containerState represents the platform agnostic pieces relating to a
 // running container's status and state
@@ -42,7 +132,11 @@ type containerState struct {
 	// InitProcessPid is the init process id in the parent namespace
 	InitProcessPid int `json:"pid"`
 	// Status is the current status of the container, running, paused, ...
+	//
+	// Deprecated: retained for backward compatibility; prefer State.
 	Status string `json:"status"`
+	// State is the typed lifecycle state corresponding to Status.
+	State ContainerState `json:"state"`
 	// Bundle is the path on the filesystem to the bundle
 	Bundle string `json:"bundle"`
 	// Rootfs is a path to a directory containing the container's root filesystem.
@@ -51,8 +145,21 @@ type containerState struct {
 	Created time.Time `json:"created"`
 	// Annotations is the user defined annotations added to the config.
 	Annotations map[string]string `json:"annotations,omitempty"`
-	// The owner of the state directory (the owner of the container).
+	// Owner is the human-readable owner of the state directory (the
+	// owner of the container): the resolved username, or "#<uid>" if
+	// it could not be resolved. Retained for backward-compatible
+	// tabular output; prefer the OwnerXXX fields below.
 	Owner string `json:"owner"`
+	// OwnerUID is the UID of the state directory's owner.
+	OwnerUID uint32 `json:"ownerUID"`
+	// OwnerGID is the GID of the state directory's owner.
+	OwnerGID uint32 `json:"ownerGID"`
+	// OwnerUser is the resolved username of OwnerUID, empty if it
+	// could not be resolved.
+	OwnerUser string `json:"ownerUser,omitempty"`
+	// OwnerGroup is the resolved group name of OwnerGID, empty if it
+	// could not be resolved.
+	OwnerGroup string `json:"ownerGroup,omitempty"`
 }
 
 // hypervisorDetails stores details of the hypervisor used to host
@@ -61,6 +168,20 @@ type hypervisorDetails struct {
 	HypervisorPath string `json:"hypervisorPath"`
 	ImagePath      string `json:"imagePath"`
 	KernelPath     string `json:"kernelPath"`
+	// KernelDigest is the sha256 digest of KernelPath's contents,
+	// empty if it could not be computed.
+	KernelDigest string `json:"kernelDigest,omitempty"`
+	// ImageDigest is the sha256 digest of ImagePath's contents, empty
+	// if it could not be computed.
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// RemoteKernelVersion is the kernel version currently published
+	// upstream, per the signed asset manifest. Empty if the manifest
+	// was not available (e.g. --offline).
+	RemoteKernelVersion string `json:"remoteKernelVersion,omitempty"`
+	// RemoteImageVersion is the image version currently published
+	// upstream, per the signed asset manifest. Empty if the manifest
+	// was not available (e.g. --offline).
+	RemoteImageVersion string `json:"remoteImageVersion,omitempty"`
 }
 
 // fullContainerState specifies the core state plus the hypervisor
@@ -80,6 +201,44 @@ type formatJSON struct{}
 type formatIDList struct{}
 type formatTabular struct{}
 
+// formatTemplate renders each fullContainerState through a user-supplied
+// text/template, optionally streaming the output through the same
+// tabwriter used by formatTabular.
+type formatTemplate struct {
+	tmpl    *template.Template
+	useTabs bool
+}
+
+// templateFuncs are made available to --format templates in addition to
+// the standard text/template functions.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	},
+}
+
+// newFormatTemplate parses format as a --format template, stripping and
+// noting the optional "table " prefix that requests tabwriter-aligned
+// output.
+func newFormatTemplate(format string) (*formatTemplate, error) {
+	useTabs := strings.HasPrefix(format, "table ")
+	if useTabs {
+		format = strings.TrimPrefix(format, "table ")
+	}
+
+	tmpl, err := template.New("list").Funcs(templateFuncs).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --format template: %v", err)
+	}
+
+	return &formatTemplate{tmpl: tmpl, useTabs: useTabs}, nil
+}
+
 var listCLICommand = cli.Command{
 	Name:  "list",
 	Usage: "lists containers started by " + name + " with the given root",
@@ -109,33 +268,138 @@ To list containers created using a non-default value for "--root":
 			Name:  "cc-all",
 			Usage: "display all available " + project + " information",
 		},
+		cli.StringSliceFlag{
+			// No "-f" shorthand: "-f" is already "--format". Use the
+			// long form here.
+			Name: "filter",
+			Usage: `filter the listed containers by one or more "key=value" expressions,
+e.g. "state=running", "id=<prefix>", "bundle=<glob>", "owner=#1000",
+"stale=kernel" or "annotation.<key>=<value>". May be repeated: repeated
+flags sharing a key are OR'ed together, distinct keys are AND'ed.
+No "-f" shorthand is provided: "-f" remains bound to "--format"`,
+		},
+		cli.StringFlag{
+			Name:  "state",
+			Usage: `shorthand for "--filter state=<value>" (configured, created, running, paused, stopped or exited)`,
+		},
+		cli.BoolFlag{
+			Name:  "watch",
+			Usage: "keep running, re-rendering the list whenever a container event occurs",
+		},
+		cli.BoolFlag{
+			Name:  "offline",
+			Usage: "suppress network access; compare assets against local configuration only",
+		},
+		cli.StringFlag{
+			Name:  "asset-manifest-url",
+			Usage: "URL of the signed manifest of latest upstream hypervisor assets, used with --cc-all",
+		},
+		cli.StringFlag{
+			Name:  "asset-manifest-pubkey",
+			Usage: "path to the ed25519 public key used to verify --asset-manifest-url's detached signature",
+		},
 	},
 	Action: func(context *cli.Context) error {
-		s, err := getContainers(context)
-		if err != nil {
-			return err
+		render := func() error {
+			s, err := getContainers(context)
+			if err != nil {
+				return err
+			}
+
+			return writeListOutput(context, s)
 		}
 
-		file := defaultOutputFile
-		showAll := context.Bool("cc-all")
+		if err := render(); err != nil {
+			return err
+		}
 
-		if context.Bool("quiet") {
-			return (&formatIDList{}).Write(s, showAll, file)
+		if !context.Bool("watch") {
+			return nil
 		}
 
-		switch context.String("format") {
-		case "table":
-			return (&formatTabular{}).Write(s, showAll, file)
+		root := context.GlobalString("root")
+		if root == "" {
+			root = defaultRootDirectory
+		}
 
-		case "json":
-			return (&formatJSON{}).Write(s, showAll, file)
+		sink, err := events.NewSink(root)
+		if err != nil {
+			return err
+		}
 
-		default:
-			return fmt.Errorf("invalid format option")
+		ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt)
+		go func() {
+			<-sigCh
+			cancel()
+		}()
+
+		// sink.Read only re-renders in response to events, and no
+		// producer is guaranteed to ever write one (the hook points in
+		// create/start/stop/delete are wired independently of list).
+		// Poll on watchPollInterval too, so --watch still converges on
+		// the true container state even when it never receives one.
+		readErrCh := make(chan error, 1)
+		go func() {
+			readErrCh <- sink.Read(ctx, time.Now(), time.Time{}, nil, true, func(events.Event) error {
+				return render()
+			})
+		}()
+
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case err := <-readErrCh:
+				return err
+			case <-ticker.C:
+				if err := render(); err != nil {
+					return err
+				}
+			}
 		}
 	},
 }
 
+// watchPollInterval bounds how stale "list --watch" output can get when
+// no event has arrived, whether because nothing happened or because the
+// event producer for the activity in question isn't wired up yet.
+const watchPollInterval = 5 * time.Second
+
+// writeListOutput renders s to defaultOutputFile according to the
+// "--quiet"/"--format" flags, as used by both a one-shot "list" and each
+// re-render of "list --watch".
+func writeListOutput(context *cli.Context, s []fullContainerState) error {
+	file := defaultOutputFile
+	showAll := context.Bool("cc-all")
+
+	if context.Bool("quiet") {
+		return (&formatIDList{}).Write(s, showAll, file)
+	}
+
+	switch context.String("format") {
+	case "table":
+		return (&formatTabular{}).Write(s, showAll, file)
+
+	case "json":
+		return (&formatJSON{}).Write(s, showAll, file)
+
+	default:
+		tmpl, err := newFormatTemplate(context.String("format"))
+		if err != nil {
+			return err
+		}
+
+		return tmpl.Write(s, showAll, file)
+	}
+}
+
 // getStaleAssetsreturns compares the two specified hypervisorDetails objects
 // and returns a list of strings representing which assets in "old" are not
 // current compared to "new". If old and new are identical, the empty string
@@ -146,8 +410,9 @@ To list containers created using a non-default value for "--root":
 // - This function is trivial because it relies upon the fact that new
 //   containers are always created with the latest versions of all assets.
 //
-// - WARNING: Since this function only compares local values, it is unable to
-//   determine if newer (remote) assets are available.
+// - This function only compares local values, so it cannot by itself
+//   determine if newer (remote) assets are available - see
+//   applyRemoteAssetVersions for that.
 func getStaleAssets(old, new hypervisorDetails) []string {
 	var stale []string
 
@@ -162,6 +427,116 @@ func getStaleAssets(old, new hypervisorDetails) []string {
 	return stale
 }
 
+// assetManifestURL returns the manifest URL to check for remote
+// staleness.
+//
+// This naturally belongs on oci.RuntimeConfig (e.g. as
+// "hypervisor.asset_manifest_url") since that is where the rest of the
+// hypervisor configuration lives; until that type grows the field, it is
+// sourced from the "--asset-manifest-url" flag.
+func assetManifestURL(context *cli.Context) string {
+	return context.String("asset-manifest-url")
+}
+
+// fetchAssetManifest fetches (and caches) the signed manifest of latest
+// upstream hypervisor assets, honouring "--offline". A nil manifest with
+// a nil error means no manifest URL is configured, or the caller asked
+// to stay offline: callers should fall back to local-only comparison.
+func fetchAssetManifest(context *cli.Context) (*assets.Manifest, error) {
+	url := assetManifestURL(context)
+	if url == "" {
+		return nil, nil
+	}
+
+	root := context.GlobalString("root")
+	if root == "" {
+		root = defaultRootDirectory
+	}
+
+	fetcher := &assets.Fetcher{
+		URL:      url,
+		CacheDir: filepath.Join(root, "assets"),
+		TTL:      time.Hour,
+		Offline:  context.Bool("offline"),
+	}
+
+	if pubKeyPath := context.String("asset-manifest-pubkey"); pubKeyPath != "" {
+		pubKey, err := loadPublicKey(pubKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		fetcher.PublicKey = pubKey
+	}
+
+	manifest, err := fetcher.Fetch()
+	if err == assets.ErrOffline {
+		return nil, nil
+	}
+
+	return manifest, err
+}
+
+// applyRemoteAssetVersions records manifest's versions onto details and
+// returns the subset of {"remote-kernel", "remote-image"} whose local
+// digest no longer matches the manifest, i.e. assets that are stale
+// relative to upstream. Returns nil if manifest is nil.
+func applyRemoteAssetVersions(details *hypervisorDetails, manifest *assets.Manifest) []string {
+	if manifest == nil {
+		return nil
+	}
+
+	details.RemoteKernelVersion = manifest.Kernel.Version
+	details.RemoteImageVersion = manifest.Image.Version
+
+	var stale []string
+
+	if manifest.Kernel.Digest != "" && details.KernelDigest != "" && manifest.Kernel.Digest != details.KernelDigest {
+		stale = append(stale, "remote-kernel")
+	}
+
+	if manifest.Image.Digest != "" && details.ImageDigest != "" && manifest.Image.Digest != details.ImageDigest {
+		stale = append(stale, "remote-image")
+	}
+
+	return stale
+}
+
+// loadPublicKey reads a raw ed25519 public key file, as produced by e.g.
+// "openssl pkey -outform DER" stripped to just the key bytes.
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key %q: expected %d bytes, got %d", path, ed25519.PublicKeySize, len(b))
+	}
+
+	return ed25519.PublicKey(b), nil
+}
+
+// fileDigest returns the sha256 digest of path's contents, prefixed
+// "sha256:" in the same style as OCI/container image digests.
+func fileDigest(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func (f *formatIDList) Write(state []fullContainerState, showAll bool, file *os.File) error {
 	for _, item := range state {
 		_, err := fmt.Fprintln(file, item.ID)
@@ -227,26 +602,97 @@ func (f *formatJSON) Write(state []fullContainerState, showAll bool, file *os.Fi
 	return json.NewEncoder(file).Encode(state)
 }
 
-// getDirOwner returns the UID of the specified directory
-func getDirOwner(dir string) (uint32, error) {
+func (f *formatTemplate) Write(state []fullContainerState, showAll bool, file *os.File) error {
+	var out io.Writer = file
+
+	var w *tabwriter.Writer
+	if f.useTabs {
+		w = tabwriter.NewWriter(file, 12, 1, 3, ' ', 0)
+		out = w
+	}
+
+	for _, item := range state {
+		if err := f.tmpl.Execute(out, item); err != nil {
+			return fmt.Errorf("invalid --format template: %v", err)
+		}
+
+		fmt.Fprintln(out)
+	}
+
+	if w != nil {
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// getDirOwner returns the UID and GID of the specified directory
+func getDirOwner(dir string) (uid, gid uint32, err error) {
 	if dir == "" {
-		return 0, errors.New("BUG: need directory")
+		return 0, 0, errors.New("BUG: need directory")
 	}
 	st, err := os.Stat(dir)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	if !st.IsDir() {
-		return 0, fmt.Errorf("%q is not a directory", dir)
+		return 0, 0, fmt.Errorf("%q is not a directory", dir)
 	}
 
 	statType, ok := st.Sys().(*syscall.Stat_t)
 	if !ok {
-		return 0, fmt.Errorf("cannot convert %+v to stat type for directory %q", st, dir)
+		return 0, 0, fmt.Errorf("cannot convert %+v to stat type for directory %q", st, dir)
+	}
+
+	return statType.Uid, statType.Gid, nil
+}
+
+// ownerResolver caches uid/gid -> name lookups across the calls to
+// getContainers made over the life of the process (e.g. every re-render
+// of "list --watch"), so /etc/passwd and /etc/group are parsed at most
+// once.
+var ownerResolver = user.NewResolver()
+
+// filterRecord adapts the fields gathered part-way through getContainers
+// to filters.Record, so a candidate container can be matched - and, if
+// it doesn't qualify, skipped - before the comparatively expensive
+// rootfs owner lookup is performed.
+type filterRecord struct {
+	id          string
+	status      string
+	state       string
+	bundle      string
+	owner       string
+	ownerUID    string
+	stale       []string
+	annotations map[string]string
+}
+
+func (r filterRecord) Field(key string) (string, bool) {
+	switch key {
+	case "id":
+		return r.id, true
+	case "status":
+		return r.status, true
+	case "state":
+		return r.state, true
+	case "bundle":
+		return r.bundle, true
+	case "owner":
+		return r.owner, r.owner != ""
+	case "ownerUID":
+		return r.ownerUID, r.ownerUID != ""
+	case "stale":
+		return strings.Join(r.stale, ","), true
+	default:
+		return "", false
 	}
+}
 
-	return statType.Uid, nil
+func (r filterRecord) Annotation(key string) (string, bool) {
+	v, ok := r.annotations[key]
+	return v, ok
 }
 
 func getContainers(context *cli.Context) ([]fullContainerState, error) {
@@ -255,8 +701,33 @@ func getContainers(context *cli.Context) ([]fullContainerState, error) {
 		return nil, errors.New("invalid runtime config")
 	}
 
+	filterTokens := context.StringSlice("filter")
+	if state := context.String("state"); state != "" {
+		filterTokens = append(filterTokens, "state="+state)
+	}
+
+	filterSet, err := filters.Parse(filterTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	earlyFilters, ownerFilter := filterSet.Without("owner")
+
 	latestHypervisorDetails := getHypervisorDetails(runtimeConfig)
 
+	// Remote-staleness detection involves a network fetch and, below,
+	// sha256-ing the (potentially large) local kernel/image files, so
+	// only do any of it when the caller actually asked to see it.
+	showAll := context.Bool("cc-all")
+
+	var manifest *assets.Manifest
+	if showAll {
+		manifest, err = fetchAssetManifest(context)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	podList, err := vci.ListPod()
 	if err != nil {
 		return nil, err
@@ -276,16 +747,58 @@ func getContainers(context *cli.Context) ([]fullContainerState, error) {
 			KernelPath:     pod.HypervisorConfig.KernelPath,
 		}
 
+		var remoteStale []string
+		if showAll {
+			currentHypervisorDetails.KernelDigest, _ = fileDigest(currentHypervisorDetails.KernelPath)
+			currentHypervisorDetails.ImageDigest, _ = fileDigest(currentHypervisorDetails.ImagePath)
+
+			remoteStale = applyRemoteAssetVersions(&currentHypervisorDetails, manifest)
+		}
+
 		for _, container := range pod.ContainersStatus {
 			ociState := oci.StatusToOCIState(container)
-			staleAssets := getStaleAssets(currentHypervisorDetails, latestHypervisorDetails)
+			staleAssets := append(getStaleAssets(currentHypervisorDetails, latestHypervisorDetails), remoteStale...)
+			state := containerStateFromStatus(ociState.Status)
+
+			rec := filterRecord{
+				id:          ociState.ID,
+				status:      ociState.Status,
+				state:       state.String(),
+				bundle:      ociState.Bundle,
+				stale:       staleAssets,
+				annotations: ociState.Annotations,
+			}
 
-			uid, err := getDirOwner(container.RootFs)
+			match, err := earlyFilters.Match(rec)
 			if err != nil {
 				return nil, err
 			}
+			if !match {
+				continue
+			}
 
-			owner := fmt.Sprintf("#%v", uid)
+			uid, gid, err := getDirOwner(container.RootFs)
+			if err != nil {
+				return nil, err
+			}
+
+			identity, err := ownerResolver.Resolve(uid, gid)
+			if err != nil {
+				return nil, err
+			}
+
+			owner := identity.Username
+			if owner == "" {
+				owner = fmt.Sprintf("#%v", uid)
+			}
+
+			rec.owner = owner
+			rec.ownerUID = strconv.FormatUint(uint64(uid), 10)
+			if match, err = ownerFilter.Match(rec); err != nil {
+				return nil, err
+			} else if !match {
+				continue
+			}
 
 			s = append(s, fullContainerState{
 				containerState: containerState{
@@ -293,11 +806,16 @@ func getContainers(context *cli.Context) ([]fullContainerState, error) {
 					ID:             ociState.ID,
 					InitProcessPid: ociState.Pid,
 					Status:         ociState.Status,
+					State:          state,
 					Bundle:         ociState.Bundle,
 					Rootfs:         container.RootFs,
 					Created:        container.StartTime,
 					Annotations:    ociState.Annotations,
 					Owner:          owner,
+					OwnerUID:       identity.UID,
+					OwnerGID:       identity.GID,
+					OwnerUser:      identity.Username,
+					OwnerGroup:     identity.Groupname,
 				},
 				CurrentHypervisorDetails: currentHypervisorDetails,
 				LatestHypervisorDetails:  latestHypervisorDetails,
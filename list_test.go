@@ -0,0 +1,188 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func testListState() []fullContainerState {
+	return []fullContainerState{
+		{
+			containerState: containerState{
+				ID:     "abc123",
+				Status: "running",
+				State:  StateRunning,
+				Owner:  "root",
+			},
+		},
+	}
+}
+
+// captureWrite runs fn against one end of an os.Pipe and returns
+// everything written to it, so formatState.Write implementations (which
+// take a concrete *os.File) can be exercised directly.
+func captureWrite(t *testing.T, fn func(f *os.File) error) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- fn(w)
+		w.Close()
+	}()
+
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read pipe: %v", err)
+	}
+
+	if err := <-writeErr; err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	return string(out)
+}
+
+func TestFormatTemplatePerRow(t *testing.T) {
+	tmpl, err := newFormatTemplate("{{.ID}} {{.Status}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := captureWrite(t, func(f *os.File) error {
+		return tmpl.Write(testListState(), false, f)
+	})
+
+	if out != "abc123 running\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestFormatTemplateTablePrefix(t *testing.T) {
+	tmpl, err := newFormatTemplate("table {{.ID}}\t{{.Owner}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !tmpl.useTabs {
+		t.Fatal("expected the \"table \" prefix to be stripped and useTabs set")
+	}
+
+	out := captureWrite(t, func(f *os.File) error {
+		return tmpl.Write(testListState(), false, f)
+	})
+
+	if !strings.Contains(out, "abc123") || !strings.Contains(out, "root") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestFormatTemplateJSONShortcut(t *testing.T) {
+	tmpl, err := newFormatTemplate("{{json .}}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := captureWrite(t, func(f *os.File) error {
+		return tmpl.Write(testListState(), false, f)
+	})
+
+	if !strings.Contains(out, `"abc123"`) {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestFormatTemplateMalformed(t *testing.T) {
+	if _, err := newFormatTemplate("{{.ID"); err == nil {
+		t.Fatal("expected an error parsing a malformed template")
+	}
+}
+
+func TestContainerStateFromStatus(t *testing.T) {
+	transitions := []struct {
+		status string
+		want   ContainerState
+	}{
+		{"configured", StateConfigured},
+		{"created", StateCreated},
+		{"running", StateRunning},
+		{"paused", StatePaused},
+		{"stopped", StateStopped},
+		{"exited", StateExited},
+		{"", StateUnknown},
+		{"some-unrecognised-status", StateUnknown},
+	}
+
+	for _, tr := range transitions {
+		if got := containerStateFromStatus(tr.status); got != tr.want {
+			t.Errorf("containerStateFromStatus(%q) = %v, want %v", tr.status, got, tr.want)
+		}
+	}
+}
+
+func TestContainerStateString(t *testing.T) {
+	strs := map[ContainerState]string{
+		StateUnknown:    "unknown",
+		StateConfigured: "configured",
+		StateCreated:    "created",
+		StateRunning:    "running",
+		StatePaused:     "paused",
+		StateStopped:    "stopped",
+		StateExited:     "exited",
+	}
+
+	for state, want := range strs {
+		if got := state.String(); got != want {
+			t.Errorf("ContainerState(%d).String() = %q, want %q", state, got, want)
+		}
+	}
+}
+
+func TestContainerStateMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(StateRunning)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if string(b) != `"running"` {
+		t.Errorf("unexpected JSON encoding: %s", b)
+	}
+}
+
+func TestFormatTemplateExecutionError(t *testing.T) {
+	tmpl, err := newFormatTemplate("{{.NoSuchField}}")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	if err := tmpl.Write(testListState(), false, devNull); err == nil {
+		t.Fatal("expected an error executing a template referencing an unknown field")
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package user resolves numeric uid/gid pairs to names by reading
+// /etc/passwd and /etc/group, caching the result in-process so repeated
+// lookups (e.g. once per container in "list") don't re-parse either file.
+package user
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Identity is a resolved container owner.
+type Identity struct {
+	UID       uint32
+	GID       uint32
+	Username  string
+	Groupname string
+}
+
+// Source abstracts reading the passwd/group databases, so callers (and
+// tests) can inject a fake one instead of the real /etc/passwd and
+// /etc/group.
+type Source interface {
+	Passwd() (io.ReadCloser, error)
+	Group() (io.ReadCloser, error)
+}
+
+// fileSource reads the system's real passwd/group databases.
+//
+// Note: this does not consult /etc/nsswitch.conf - only the "files"
+// source is supported, which covers the common case.
+type fileSource struct{}
+
+func (fileSource) Passwd() (io.ReadCloser, error) { return os.Open("/etc/passwd") }
+func (fileSource) Group() (io.ReadCloser, error) { return os.Open("/etc/group") }
+
+// Resolver resolves uids/gids to names, caching results in-process.
+type Resolver struct {
+	source Source
+
+	mu     sync.Mutex
+	users  map[uint32]string
+	groups map[uint32]string
+	loaded bool
+}
+
+// NewResolver returns a Resolver backed by the system's /etc/passwd and
+// /etc/group.
+func NewResolver() *Resolver {
+	return NewResolverWithSource(fileSource{})
+}
+
+// NewResolverWithSource returns a Resolver backed by the given Source,
+// e.g. a fake passwd/group pair in tests.
+func NewResolverWithSource(source Source) *Resolver {
+	return &Resolver{source: source}
+}
+
+// Resolve returns the Identity for uid/gid. Username/Groupname are left
+// empty if either database has no matching entry.
+func (r *Resolver) Resolve(uid, gid uint32) (Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.loaded {
+		if err := r.load(); err != nil {
+			return Identity{}, err
+		}
+		r.loaded = true
+	}
+
+	return Identity{
+		UID:       uid,
+		GID:       gid,
+		Username:  r.users[uid],
+		Groupname: r.groups[gid],
+	}, nil
+}
+
+func (r *Resolver) load() error {
+	users, err := parseIDNames(r.source.Passwd, 0, 2)
+	if err != nil {
+		return err
+	}
+
+	groups, err := parseIDNames(r.source.Group, 0, 2)
+	if err != nil {
+		return err
+	}
+
+	r.users = users
+	r.groups = groups
+
+	return nil
+}
+
+// parseIDNames parses a colon-separated database (passwd or group
+// format) where the name is at nameField and the numeric id is at
+// idField, returning a map of id to name.
+func parseIDNames(open func() (io.ReadCloser, error), nameField, idField int) (map[uint32]string, error) {
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(map[uint32]string)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) <= idField {
+			continue
+		}
+
+		id, err := strconv.ParseUint(fields[idField], 10, 32)
+		if err != nil {
+			continue
+		}
+
+		names[uint32(id)] = fields[nameField]
+	}
+
+	return names, scanner.Err()
+}
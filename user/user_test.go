@@ -0,0 +1,123 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package user
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// fakeSource is a Source backed by in-memory passwd/group contents, so
+// tests don't depend on the real /etc/passwd and /etc/group.
+type fakeSource struct {
+	passwd string
+	group  string
+}
+
+func (s fakeSource) Passwd() (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(s.passwd)), nil
+}
+
+func (s fakeSource) Group() (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(s.group)), nil
+}
+
+const fakePasswd = `root:x:0:0:root:/root:/bin/bash
+alice:x:1000:1000:Alice:/home/alice:/bin/bash
+`
+
+const fakeGroup = `root:x:0:
+alice:x:1000:
+`
+
+func TestResolverResolve(t *testing.T) {
+	r := NewResolverWithSource(fakeSource{passwd: fakePasswd, group: fakeGroup})
+
+	id, err := r.Resolve(1000, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Identity{UID: 1000, GID: 1000, Username: "alice", Groupname: "alice"}
+	if id != want {
+		t.Errorf("Resolve(1000, 1000) = %+v, want %+v", id, want)
+	}
+}
+
+func TestResolverResolveRoot(t *testing.T) {
+	r := NewResolverWithSource(fakeSource{passwd: fakePasswd, group: fakeGroup})
+
+	id, err := r.Resolve(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Identity{UID: 0, GID: 0, Username: "root", Groupname: "root"}
+	if id != want {
+		t.Errorf("Resolve(0, 0) = %+v, want %+v", id, want)
+	}
+}
+
+func TestResolverResolveUnknown(t *testing.T) {
+	r := NewResolverWithSource(fakeSource{passwd: fakePasswd, group: fakeGroup})
+
+	id, err := r.Resolve(4242, 4242)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id.Username != "" || id.Groupname != "" {
+		t.Errorf("expected an unresolved uid/gid to yield empty names, got %+v", id)
+	}
+}
+
+func TestResolverCachesAcrossCalls(t *testing.T) {
+	source := &countingSource{fakeSource: fakeSource{passwd: fakePasswd, group: fakeGroup}}
+	r := NewResolverWithSource(source)
+
+	if _, err := r.Resolve(0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Resolve(1000, 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if source.passwdOpens != 1 {
+		t.Errorf("expected /etc/passwd to be parsed once, got %d opens", source.passwdOpens)
+	}
+	if source.groupOpens != 1 {
+		t.Errorf("expected /etc/group to be parsed once, got %d opens", source.groupOpens)
+	}
+}
+
+// countingSource wraps fakeSource to count how many times each database
+// is actually read, proving the Resolver caches.
+type countingSource struct {
+	fakeSource
+	passwdOpens int
+	groupOpens  int
+}
+
+func (s *countingSource) Passwd() (io.ReadCloser, error) {
+	s.passwdOpens++
+	return s.fakeSource.Passwd()
+}
+
+func (s *countingSource) Group() (io.ReadCloser, error) {
+	s.groupOpens++
+	return s.fakeSource.Group()
+}